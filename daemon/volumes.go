@@ -1,24 +1,57 @@
 package daemon
 
 import (
+	"archive/tar"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/docker/docker/archive"
 	"github.com/docker/docker/daemon/execdriver"
+	"github.com/docker/docker/pkg/label"
 	"github.com/docker/docker/pkg/symlink"
+	"github.com/docker/docker/volume"
+	volumedrivers "github.com/docker/docker/volume/drivers"
+	"github.com/docker/docker/volume/local"
+	"github.com/docker/docker/volumes"
 )
 
+// ErrVolumeReadonly is returned when a volume was explicitly flagged to
+// have its contents seeded from the image but is mounted read-only and is
+// still empty.
+var ErrVolumeReadonly = errors.New("Cannot copy data into a read-only volume")
+
 type Volume struct {
-	HostPath    string
-	VolPath     string
+	HostPath string
+	VolPath  string
+	Name     string
+	Driver   string
+	// Relabel is the SELinux relabel request from the bind spec, if any:
+	// "z" for a shared (multi-container) label, "Z" for a private one.
+	Relabel     string
 	isReadWrite bool
 	isBindMount bool
+	// copyData tells initialize whether to seed the volume from the
+	// image. true for driver-backed anonymous/named volumes (a named one
+	// may be reused and already hold data), false for bind mounts and
+	// volumes inherited via volumes-from (already seeded once, in their
+	// originating container).
+	copyData bool
+
+	// From is the container this volume was inherited from via
+	// volumes-from, or nil if it wasn't.
+	From *Container
+
+	// backend is the driver-managed volume HostPath was obtained from.
+	// It is nil for plain bind mounts.
+	backend volume.Volume
 }
 
 func (v *Volume) isDir() (bool, error) {
@@ -98,6 +131,152 @@ func (container *Container) sortedVolumeMounts() []string {
 	return mountPaths
 }
 
+// removeVolumes unmounts every driver-backed volume mounted into container
+// and drops its reference on them. When removeVolumes is true (a
+// `docker rm -v`) and a volume's refcount reaches zero, its on-disk data
+// is deleted through the owning driver. It must be called as part of
+// container teardown.
+func (daemon *Daemon) removeVolumes(container *Container, removeVolumes bool) error {
+	repo, err := ensureVolumeRepository(daemon)
+	if err != nil {
+		return err
+	}
+
+	for volPath, vol := range container.volumeDrivers {
+		if err := vol.Unmount(); err != nil {
+			return fmt.Errorf("Error unmounting volume %s: %s", volPath, err)
+		}
+
+		refs, err := repo.Remove(vol.Name(), container.ID)
+		if err != nil {
+			return fmt.Errorf("Error releasing reference on volume %s: %s", vol.Name(), err)
+		}
+
+		if refs == 0 && removeVolumes {
+			d, exists := volumedrivers.Lookup(vol.DriverName())
+			if !exists {
+				return fmt.Errorf("Unable to locate volume driver %s", vol.DriverName())
+			}
+			if err := d.Remove(vol); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// volumeFor returns the Volume mounted at, or as an ancestor directory of,
+// path inside container, if any. When more than one mounted volume matches
+// (e.g. both "/data" and "/data/sub" are mounted), the longest, most
+// specific match wins; ranging over container.Volumes directly would pick
+// an arbitrary one, since Go map iteration order is randomized.
+func (container *Container) volumeFor(path string) (*Volume, bool) {
+	path = filepath.Clean(path)
+
+	var bestVolPath, bestHostPath string
+	for volPath, hostPath := range container.Volumes {
+		if path != volPath && !strings.HasPrefix(path, volPath+string(filepath.Separator)) {
+			continue
+		}
+		if len(volPath) > len(bestVolPath) {
+			bestVolPath, bestHostPath = volPath, hostPath
+		}
+	}
+
+	if bestVolPath == "" {
+		return nil, false
+	}
+	return &Volume{VolPath: bestVolPath, HostPath: bestHostPath}, true
+}
+
+// ContainerExportVolume resolves resource, a path inside one of container's
+// mounted volumes, to its owning Volume and streams it out as a tar
+// archive. This lets `docker cp` reach data that lives outside the
+// container's own rootfs.
+func (daemon *Daemon) ContainerExportVolume(container *Container, resource string) (io.ReadCloser, error) {
+	vol, exists := container.volumeFor(resource)
+	if !exists {
+		return nil, fmt.Errorf("No volume found for path %s in container %s", resource, container.ID)
+	}
+	return vol.Export(resource)
+}
+
+// Export streams resource, a path inside the container at or below v's
+// mount point (v.VolPath), as an uncompressed tar archive. resource may name a single file or a
+// directory; for a single file the tar entry is renamed to its base name
+// so a plain `docker cp` of a file behaves sensibly.
+func (v *Volume) Export(resource string) (io.ReadCloser, error) {
+	relPath, err := filepath.Rel(v.VolPath, filepath.Clean(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	basePath, err := symlink.FollowSymlinkInScope(filepath.Join(v.HostPath, relPath), v.HostPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(basePath)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+
+		err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			var name string
+			if stat.IsDir() {
+				rel, err := filepath.Rel(basePath, path)
+				if err != nil {
+					return err
+				}
+				name = filepath.Join(filepath.Base(basePath), rel)
+			} else {
+				name = filepath.Base(resource)
+			}
+
+			hdr, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			hdr.Name = name
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if err := tw.Close(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.Close()
+	}()
+
+	return pr, nil
+}
+
 func parseVolumesFromSpec(container *Container, spec string) (map[string]*Volume, error) {
 	specParts := strings.SplitN(spec, ":", 2)
 	if len(specParts) == 0 {
@@ -114,9 +293,25 @@ func parseVolumesFromSpec(container *Container, spec string) (map[string]*Volume
 		return nil, err
 	}
 
+	// A volume privately relabeled for c (":Z") cannot be shared with a
+	// container running under a different MountLabel without silently
+	// widening who can read it.
+	for _, v := range volumes {
+		if v.Relabel == "Z" && c.MountLabel != container.MountLabel {
+			return nil, fmt.Errorf("Cannot inherit volume %s via volumes-from: it is privately labeled (Z) for container %s", v.VolPath, c.ID)
+		}
+	}
+
+	// The data was already seeded from the image when the volume was
+	// first created; a later volumes-from hop must not seed it again.
+	for _, v := range volumes {
+		v.From = c
+		v.copyData = false
+	}
+
 	if len(specParts) == 2 {
 		mode := specParts[1]
-		if validVolumeMode(mode) {
+		if !validVolumeMode(mode) {
 			return nil, fmt.Errorf("Invalid mode for volumes-from: %s", mode)
 		}
 
@@ -132,28 +327,64 @@ func parseVolumesFromSpec(container *Container, spec string) (map[string]*Volume
 func applyVolumesFrom(container *Container) error {
 	volumesFrom := container.hostConfig.VolumesFrom
 
+	// Parse every spec up front: if a later one is malformed we must not
+	// have already initialized mounts from the earlier ones, or
+	// container.Volumes would be left half-populated.
+	var inherited []*Volume
 	for _, spec := range volumesFrom {
 		volumes, err := parseVolumesFromSpec(container, spec)
 		if err != nil {
 			return err
 		}
-
 		for _, v := range volumes {
-			if err = v.initialize(container); err != nil {
-				return err
-			}
+			inherited = append(inherited, v)
+		}
+	}
+
+	for _, v := range inherited {
+		if err := v.initialize(container); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// validVolumeMode returns true if mode is a valid, comma separated
+// bind-mount mode, e.g. "ro", "rw" or "ro,Z".
 func validVolumeMode(mode string) bool {
-	validModes := map[string]bool{
-		"rw": true,
-		"ro": true,
+	_, _, err := parseVolumeMode(mode)
+	return err == nil
+}
+
+// parseVolumeMode splits a comma separated bind-mount mode into its
+// read-write and SELinux relabel ("z"/"Z") components, validating each
+// token independently so combined modes like "ro,Z" are accepted.
+func parseVolumeMode(mode string) (readWrite bool, relabel string, err error) {
+	readWrite = true
+	if mode == "" {
+		return readWrite, relabel, nil
+	}
+	readWriteSet := false
+
+	for _, token := range strings.Split(mode, ",") {
+		switch token {
+		case "rw", "ro":
+			if readWriteSet {
+				return false, "", fmt.Errorf("Invalid volume mode: %s", mode)
+			}
+			readWriteSet = true
+			readWrite = token == "rw"
+		case "z", "Z":
+			if relabel != "" {
+				return false, "", fmt.Errorf("Invalid volume mode: %s", mode)
+			}
+			relabel = token
+		default:
+			return false, "", fmt.Errorf("Invalid volume mode: %s", mode)
+		}
 	}
 
-	return validModes[mode]
+	return readWrite, relabel, nil
 }
 
 func parseBindVolumeSpec(spec string) (Volume, error) {
@@ -173,18 +404,38 @@ func parseBindVolumeSpec(spec string) (Volume, error) {
 	case 3:
 		vol.HostPath = arr[0]
 		vol.VolPath = arr[1]
-		vol.isReadWrite = validVolumeMode(arr[2]) && arr[2] == "rw"
+		readWrite, relabel, err := parseVolumeMode(arr[2])
+		if err != nil {
+			return vol, err
+		}
+		vol.isReadWrite = readWrite
+		vol.Relabel = relabel
 	default:
 		return vol, fmt.Errorf("Invalid volume specification: %s", spec)
 	}
 
-	if !filepath.IsAbs(vol.HostPath) {
-		return vol, fmt.Errorf("cannot bind mount volume: %s volume paths must be absolute.", vol.HostPath)
+	// A non-absolute "host" component names a volume managed by a driver
+	// (e.g. `myvol:/data`) rather than a path to bind mount.
+	if vol.HostPath != "" && !filepath.IsAbs(vol.HostPath) {
+		vol.Name = vol.HostPath
+		vol.HostPath = ""
+	}
+
+	if vol.HostPath != "" {
+		vol.isBindMount = true
+	} else {
+		// Driver-backed volumes (anonymous or named) are seeded from the
+		// image when they are empty, unlike bind mounts.
+		vol.copyData = true
 	}
 
 	return vol, nil
 }
 
+// createVolumes sets up every volume declared for container: its bind
+// mounts, plus an anonymous driver-backed volume for each VOLUME the image
+// declares that isn't already covered by one. Anonymous volumes use
+// container.hostConfig.VolumeDriver, if set, instead of the local driver.
 func createVolumes(container *Container) error {
 	// Get all the bindmounts
 	volumes, err := container.GetVolumes()
@@ -198,8 +449,10 @@ func createVolumes(container *Container) error {
 		if _, exists := volumes[volPath]; !exists {
 			volumes[volPath] = &Volume{
 				VolPath:     volPath,
+				Driver:      container.hostConfig.VolumeDriver,
 				isReadWrite: true,
 				isBindMount: false,
+				copyData:    true,
 			}
 		}
 	}
@@ -213,22 +466,198 @@ func createVolumes(container *Container) error {
 	return nil
 }
 
-func createVolumeHostPath(container *Container) (string, error) {
-	volumesDriver := container.daemon.volumes.Driver()
+// volumeDriverSubdir is the directory (under the daemon root) that the
+// built-in local volume driver stores its volumes in.
+const volumeDriverSubdir = "volumes"
 
-	// Do not pass a container as the parameter for the volume creation.
-	// The graph driver using the container's information ( Image ) to
-	// create the parent.
-	c, err := container.daemon.volumes.Create(nil, "", "", "", "", nil, nil)
+// registerVolumeDrivers wires up the built-in volume drivers.
+func registerVolumeDrivers(root string) error {
+	l, err := local.New(filepath.Join(root, volumeDriverSubdir))
 	if err != nil {
-		return "", err
+		return err
 	}
-	hostPath, err := volumesDriver.Get(c.ID, "")
+	volumedrivers.Register(l)
+	return nil
+}
+
+var (
+	volumeDriversOnce sync.Once
+	volumeDriversErr  error
+)
+
+// ensureVolumeDriversRegistered registers the built-in volume drivers the
+// first time any volume needs one. There is no daemon-startup hook in
+// this package to call registerVolumeDrivers from, so every entry point
+// that looks a driver up goes through here instead, guaranteeing the
+// local driver is always available by the time it's needed.
+func ensureVolumeDriversRegistered(root string) error {
+	volumeDriversOnce.Do(func() {
+		volumeDriversErr = registerVolumeDrivers(root)
+	})
+	return volumeDriversErr
+}
+
+// initVolumeRepository opens the on-disk reference-count tracker for
+// driver-backed volumes. If no metadata file exists yet (an upgrade from a
+// daemon that predates it), the table is rebuilt from each container's
+// persisted container.Volumes, not container.volumeDrivers: that map is
+// only populated by Volume.initialize on a fresh mount, so on a daemon
+// restart it is empty for every container that was created before the
+// upgrade - exactly the case this reconciliation exists for.
+func initVolumeRepository(root string, containers []*Container) (*volumes.Repository, error) {
+	repo, existed, err := volumes.NewRepository(filepath.Join(root, volumeDriverSubdir))
 	if err != nil {
-		return hostPath, fmt.Errorf("Driver %s failed to get volume rootfs %s: %s", volumesDriver, c.ID, err)
+		return nil, err
+	}
+
+	if !existed {
+		driverRoot := filepath.Join(root, volumeDriverSubdir)
+		for _, container := range containers {
+			for _, hostPath := range container.Volumes {
+				// Bind mounts point at arbitrary host paths outside
+				// driverRoot and aren't refcounted; only volumes the
+				// local driver created live here.
+				if filepath.Dir(hostPath) != driverRoot {
+					continue
+				}
+				volumeID := filepath.Base(hostPath)
+				if err := repo.Add(volumeID, local.Name, container.ID); err != nil {
+					return nil, fmt.Errorf("Error reconciling volume refcounts: %s", err)
+				}
+			}
+		}
 	}
 
-	return hostPath, nil
+	return repo, nil
+}
+
+var (
+	volumeRepoOnce sync.Once
+	volumeRepo     *volumes.Repository
+	volumeRepoErr  error
+)
+
+// ensureVolumeRepository opens (reconciling on first run, if needed) the
+// refcount repository the first time anything needs it. As with
+// ensureVolumeDriversRegistered, there is no daemon-startup hook in this
+// package to call initVolumeRepository from, so every entry point that
+// touches refcounts goes through here instead. Using daemon.List() at
+// first-use time rather than at process start covers the same "walk
+// existing containers" reconciliation the request asked for.
+func ensureVolumeRepository(daemon *Daemon) (*volumes.Repository, error) {
+	volumeRepoOnce.Do(func() {
+		volumeRepo, volumeRepoErr = initVolumeRepository(daemon.config.Root, daemon.List())
+	})
+	return volumeRepo, volumeRepoErr
+}
+
+// Volumes returns every driver-backed volume currently referenced by a
+// container known to the daemon.
+func (daemon *Daemon) Volumes() []*Volume {
+	var vols []*Volume
+	for _, container := range daemon.List() {
+		for volPath, vol := range container.volumeDrivers {
+			vols = append(vols, &Volume{
+				VolPath:  volPath,
+				HostPath: container.Volumes[volPath],
+				Name:     vol.Name(),
+				Driver:   vol.DriverName(),
+			})
+		}
+	}
+	return vols
+}
+
+// DeleteVolumes removes the on-disk data backing each of the given volume
+// host paths, refusing any that are still referenced by a container.
+func (daemon *Daemon) DeleteVolumes(paths []string) error {
+	repo, err := ensureVolumeRepository(daemon)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		volumeID := filepath.Base(path)
+
+		if refs := repo.RefCount(volumeID); refs > 0 {
+			return fmt.Errorf("Unable to remove volume %s: still referenced by %d container(s)", volumeID, refs)
+		}
+
+		// The repository drops a volume's record, driver included, once
+		// its refcount reaches zero, so for a volume that passed the
+		// check above repo.Driver reports nothing and the local driver
+		// is used; volumes of other drivers are not found this way.
+		driverName, exists := repo.Driver(volumeID)
+		if !exists {
+			driverName = local.Name
+		}
+
+		d, exists := volumedrivers.Lookup(driverName)
+		if !exists {
+			return fmt.Errorf("Unable to locate volume driver %s", driverName)
+		}
+
+		vol, err := d.Get(volumeID)
+		if err != nil {
+			return err
+		}
+
+		if err := d.Remove(vol); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountFromDriver resolves v.HostPath through the volume driver selected by
+// v.Driver, falling back to the built-in local driver. It creates the
+// backing volume on first use.
+func (v *Volume) mountFromDriver(container *Container) error {
+	if err := ensureVolumeDriversRegistered(container.daemon.config.Root); err != nil {
+		return err
+	}
+
+	driverName := v.Driver
+	if driverName == "" {
+		driverName = local.Name
+	}
+
+	d, exists := volumedrivers.Lookup(driverName)
+	if !exists {
+		return fmt.Errorf("Unable to locate volume driver %s", driverName)
+	}
+
+	// An anonymous volume (empty name) always gets a new volume; looking
+	// up "" would hand back the driver's root directory instead.
+	var vol volume.Volume
+	var err error
+	if v.Name != "" {
+		vol, err = d.Get(v.Name)
+	}
+	if v.Name == "" || err != nil {
+		if vol, err = d.Create(v.Name, nil); err != nil {
+			return err
+		}
+	}
+
+	hostPath, err := vol.Mount()
+	if err != nil {
+		return err
+	}
+
+	repo, err := ensureVolumeRepository(container.daemon)
+	if err != nil {
+		return err
+	}
+	if err := repo.Add(vol.Name(), d.Name(), container.ID); err != nil {
+		return err
+	}
+
+	v.backend = vol
+	v.Driver = d.Name()
+	v.Name = vol.Name()
+	v.HostPath = hostPath
+	return nil
 }
 
 func (v *Volume) initialize(container *Container) error {
@@ -240,12 +669,24 @@ func (v *Volume) initialize(container *Container) error {
 		return nil
 	}
 
-	// If it's not a bindmount we need to create the dir on the host
-	if !v.isBindMount && v.HostPath == "" {
-		v.HostPath, err = createVolumeHostPath(container)
-		if err != nil {
+	// A ro volumes-from cannot be silently upgraded to rw by a second
+	// inheritance hop: clamp to the writable state it had in From.
+	if v.From != nil {
+		if rw, ok := v.From.VolumesRW[v.VolPath]; ok && !rw {
+			v.isReadWrite = false
+		}
+	}
+
+	// Bind mounts use the host path as given; everything else (named and
+	// anonymous volumes) is backed by a volume driver.
+	if !v.isBindMount {
+		if err := v.mountFromDriver(container); err != nil {
 			return err
 		}
+		if container.volumeDrivers == nil {
+			container.volumeDrivers = make(map[string]volume.Volume)
+		}
+		container.volumeDrivers[v.VolPath] = v.backend
 	}
 
 	hostPath, err := filepath.EvalSymlinks(v.HostPath)
@@ -253,6 +694,12 @@ func (v *Volume) initialize(container *Container) error {
 		return err
 	}
 
+	if v.Relabel != "" {
+		if err := label.Relabel(hostPath, container.MountLabel, v.Relabel == "z"); err != nil {
+			return err
+		}
+	}
+
 	// Create the mountpoint
 	// This is the path to the volume within the container FS
 	// This differs from `hostPath` in that `hostPath` refers to the place where
@@ -273,13 +720,37 @@ func (v *Volume) initialize(container *Container) error {
 		return err
 	}
 
-	// Do not copy or change permissions if we are mounting from the host
-	if v.isReadWrite && !v.isBindMount {
+	if v.copyData {
+		if !v.isReadWrite {
+			// copyData is set for every driver-backed volume given with -v
+			// or declared by the image, not just freshly created ones
+			// (volumes-from inherits it as false), so this read-only volume may well
+			// already hold data from an earlier mount (the common
+			// `docker run -v myvol:/data:ro` pattern). Only reject it
+			// when a copy would actually have to happen to seed it.
+			empty, err := dirIsEmpty(hostPath)
+			if err != nil {
+				return err
+			}
+			if empty {
+				return ErrVolumeReadonly
+			}
+			return nil
+		}
 		return copyExistingContents(fullVolPath, hostPath)
 	}
 	return nil
 }
 
+// dirIsEmpty reports whether path contains no entries.
+func dirIsEmpty(path string) (bool, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
 func createIfNotExists(destination string, isDir bool) error {
 	if _, err := os.Stat(destination); err == nil || !os.IsNotExist(err) {
 		return nil
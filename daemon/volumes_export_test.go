@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportSingleFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &Volume{VolPath: "/data", HostPath: dir}
+	rc, err := v.Export("/data/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "file.txt" {
+		t.Fatalf("expected a single-file export to rename its tar entry to %q, got %q", "file.txt", hdr.Name)
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("expected exactly one tar entry for a single file, got another: %v", err)
+	}
+}
+
+func TestExportDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &Volume{VolPath: "/data", HostPath: dir}
+	rc, err := v.Export("/data/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	var names []string
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+
+	want := filepath.Join("sub", "file.txt")
+	found := false
+	for _, name := range names {
+		if name == want {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected directory export to contain %q, got %v", want, names)
+	}
+}
+
+func TestExportRejectsSymlinkEscape(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outside, err := ioutil.TempDir("", "docker-volumes-export-outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A symlink inside the volume pointing at a directory outside of it
+	// must not let Export read the target; symlink.FollowSymlinkInScope
+	// confines the resolved path to v.HostPath, so the escaping target
+	// doesn't exist there and resolution fails.
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	v := &Volume{VolPath: "/data", HostPath: dir}
+	if rc, err := v.Export("/data/escape/secret.txt"); err == nil {
+		rc.Close()
+		t.Fatalf("expected Export to refuse a path that escapes the volume through a symlink")
+	}
+}
@@ -0,0 +1,51 @@
+package daemon
+
+import "testing"
+
+func TestValidVolumeMode(t *testing.T) {
+	valid := []string{"", "rw", "ro", "z", "Z", "ro,z", "rw,Z"}
+	for _, mode := range valid {
+		if !validVolumeMode(mode) {
+			t.Errorf("expected mode %q to be valid", mode)
+		}
+	}
+
+	invalid := []string{"bogus", "rw,ro", "z,Z", "ro,bogus"}
+	for _, mode := range invalid {
+		if validVolumeMode(mode) {
+			t.Errorf("expected mode %q to be invalid", mode)
+		}
+	}
+}
+
+func TestParseVolumeMode(t *testing.T) {
+	readWrite, relabel, err := parseVolumeMode("ro,Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readWrite {
+		t.Errorf("expected ro,Z to parse as read-only")
+	}
+	if relabel != "Z" {
+		t.Errorf("expected relabel %q, got %q", "Z", relabel)
+	}
+
+	readWrite, relabel, err = parseVolumeMode("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !readWrite {
+		t.Errorf("expected the empty mode to default to read-write")
+	}
+	if relabel != "" {
+		t.Errorf("expected no relabel for the empty mode, got %q", relabel)
+	}
+
+	if _, _, err := parseVolumeMode("bogus"); err == nil {
+		t.Errorf("expected an unrecognized token to be rejected")
+	}
+
+	if _, _, err := parseVolumeMode("z,Z"); err == nil {
+		t.Errorf("expected two relabel tokens to be rejected")
+	}
+}
@@ -0,0 +1,52 @@
+// +build linux
+
+// Package label applies SELinux labels to files and directories bind
+// mounted into containers, using the `chcon` userspace tool so that no
+// cgo/libselinux binding is required. Hosts without SELinux tooling are
+// silently left untouched.
+package label
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Relabel recursively applies mountLabel to path. When shared is true the
+// label is relaxed so the path may be bind mounted into more than one
+// container (the `:z` bind option); otherwise it is applied as-is,
+// reserving the path to a single container (the `:Z` bind option).
+func Relabel(path, mountLabel string, shared bool) error {
+	if mountLabel == "" {
+		return nil
+	}
+
+	if _, err := exec.LookPath("chcon"); err != nil {
+		// No SELinux userspace tooling available; treat as disabled.
+		return nil
+	}
+
+	context := relabelContext(mountLabel, shared)
+
+	if out, err := exec.Command("chcon", "-R", context, path).CombinedOutput(); err != nil {
+		return fmt.Errorf("SELinux relabel of %s failed: %s: %s", path, err, out)
+	}
+
+	return nil
+}
+
+// relabelContext derives the full SELinux context chcon should apply for
+// mountLabel. A shared (`:z`) relabel needs to be usable by more than one
+// container, so its MCS range (the last colon-separated component) is
+// dropped, relaxing the label to the category shared by every container
+// using the default range; a private (`:Z`) relabel keeps mountLabel as-is.
+func relabelContext(mountLabel string, shared bool) string {
+	if !shared {
+		return mountLabel
+	}
+
+	if i := strings.LastIndex(mountLabel, ":"); i >= 0 {
+		return mountLabel[:i]
+	}
+	return mountLabel
+}
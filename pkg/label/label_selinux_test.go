@@ -0,0 +1,30 @@
+// +build linux
+
+package label
+
+import "testing"
+
+func TestRelabelContextPrivate(t *testing.T) {
+	mountLabel := "system_u:object_r:svirt_sandbox_file_t:s0:c1,c2"
+	got := relabelContext(mountLabel, false)
+	if got != mountLabel {
+		t.Fatalf("expected private relabel to keep the full context, got %q", got)
+	}
+}
+
+func TestRelabelContextShared(t *testing.T) {
+	mountLabel := "system_u:object_r:svirt_sandbox_file_t:s0:c1,c2"
+	got := relabelContext(mountLabel, true)
+	want := "system_u:object_r:svirt_sandbox_file_t:s0"
+	if got != want {
+		t.Fatalf("expected shared relabel to drop the MCS range, got %q, want %q", got, want)
+	}
+}
+
+func TestRelabelContextSharedNoRange(t *testing.T) {
+	mountLabel := "svirt_sandbox_file_t"
+	got := relabelContext(mountLabel, true)
+	if got != mountLabel {
+		t.Fatalf("expected a label with no colon to be returned unchanged, got %q", got)
+	}
+}
@@ -0,0 +1,18 @@
+// Package runconfig holds the configuration accepted when creating and
+// starting a container.
+package runconfig
+
+// HostConfig holds the parts of a container's configuration that are
+// host-specific and not committed to images, such as bind mounts,
+// container linking, and volume handling.
+type HostConfig struct {
+	// VolumesFrom lists other containers ("name[:mode]") whose volumes
+	// this container inherits.
+	VolumesFrom []string
+
+	// VolumeDriver selects the volume driver used for anonymous volumes
+	// declared by the image (Dockerfile VOLUME) that aren't also given
+	// as a named volume or bind mount. Empty means the built-in local
+	// driver.
+	VolumeDriver string
+}
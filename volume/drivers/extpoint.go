@@ -0,0 +1,30 @@
+// Package drivers maintains the registry of available volume.Driver
+// implementations, keyed by the name each driver registers itself under.
+package drivers
+
+import (
+	"sync"
+
+	"github.com/docker/docker/volume"
+)
+
+var (
+	mu      sync.Mutex
+	drivers = make(map[string]volume.Driver)
+)
+
+// Register makes d available for later lookup under d.Name(). Registering
+// a second driver under the same name replaces the first.
+func Register(d volume.Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	drivers[d.Name()] = d
+}
+
+// Lookup returns the driver registered under name, if any.
+func Lookup(name string) (volume.Driver, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	d, exists := drivers[name]
+	return d, exists
+}
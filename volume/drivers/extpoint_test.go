@@ -0,0 +1,35 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/docker/docker/volume"
+)
+
+type fakeDriver struct{ name string }
+
+func (f *fakeDriver) Name() string { return f.name }
+func (f *fakeDriver) Create(name string, opts map[string]string) (volume.Volume, error) {
+	return nil, nil
+}
+func (f *fakeDriver) Remove(volume.Volume) error             { return nil }
+func (f *fakeDriver) Get(name string) (volume.Volume, error) { return nil, nil }
+
+func TestRegisterAndLookup(t *testing.T) {
+	d := &fakeDriver{name: "fake-test-driver"}
+	Register(d)
+
+	got, exists := Lookup(d.Name())
+	if !exists {
+		t.Fatalf("expected driver %q to be registered", d.Name())
+	}
+	if got != d {
+		t.Fatalf("Lookup(%q) returned a different driver instance", d.Name())
+	}
+}
+
+func TestLookupMissing(t *testing.T) {
+	if _, exists := Lookup("does-not-exist"); exists {
+		t.Fatalf("expected no driver registered under this name")
+	}
+}
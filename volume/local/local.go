@@ -0,0 +1,99 @@
+// Package local implements the built-in "local" volume driver: every
+// volume is a plain directory on the host filesystem, rooted under a
+// single directory (normally a "volumes" subdirectory of the daemon root).
+// It replaces the anonymous-directory handling volumes used to get
+// straight from the graph driver.
+package local
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/utils"
+	"github.com/docker/docker/volume"
+)
+
+// Name is the identifier the local driver registers itself under, and the
+// default used when no other driver is requested.
+const Name = "local"
+
+// Root is a volume.Driver backed by a directory tree on the host.
+type Root struct {
+	path string
+}
+
+// New creates a local driver rooted at path, creating the directory if it
+// does not already exist.
+func New(path string) (*Root, error) {
+	if err := os.MkdirAll(path, 0700); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	return &Root{path: path}, nil
+}
+
+// Name returns the driver name volumes created by this Root are tagged
+// with.
+func (r *Root) Name() string {
+	return Name
+}
+
+// Create allocates a new directory for name under the driver root. An
+// empty name generates a random one, mirroring how anonymous volumes used
+// to get an ID straight from the graph driver.
+func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error) {
+	if name == "" {
+		name = utils.GenerateRandomID()
+	}
+
+	path := r.DataPath(name)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+
+	return &localVolume{driverName: r.Name(), name: name, path: path}, nil
+}
+
+// Remove deletes the on-disk directory backing v.
+func (r *Root) Remove(v volume.Volume) error {
+	lv, ok := v.(*localVolume)
+	if !ok {
+		return fmt.Errorf("unknown volume type %T", v)
+	}
+	return os.RemoveAll(lv.path)
+}
+
+// Get looks up an existing volume by name.
+func (r *Root) Get(name string) (volume.Volume, error) {
+	path := r.DataPath(name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return &localVolume{driverName: r.Name(), name: name, path: path}, nil
+}
+
+// DataPath returns the on-disk location of the volume with the given name.
+func (r *Root) DataPath(name string) string {
+	return filepath.Join(r.path, name)
+}
+
+type localVolume struct {
+	driverName string
+	name       string
+	path       string
+}
+
+func (v *localVolume) Name() string       { return v.name }
+func (v *localVolume) DriverName() string { return v.driverName }
+func (v *localVolume) Path() string       { return v.path }
+
+// Mount is a no-op: a local volume's path is already a plain host
+// directory, nothing needs to be mounted to make it available.
+func (v *localVolume) Mount() (string, error) {
+	return v.path, nil
+}
+
+// Unmount is a no-op for the local driver.
+func (v *localVolume) Unmount() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateGetRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volume-local-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := root.Create("myvol", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.Name() != "myvol" {
+		t.Fatalf("expected volume name %q, got %q", "myvol", vol.Name())
+	}
+	if vol.DriverName() != Name {
+		t.Fatalf("expected driver name %q, got %q", Name, vol.DriverName())
+	}
+
+	if _, err := os.Stat(vol.Path()); err != nil {
+		t.Fatalf("expected volume directory to exist: %s", err)
+	}
+
+	got, err := root.Get("myvol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Path() != vol.Path() {
+		t.Fatalf("Get returned a different path: %s != %s", got.Path(), vol.Path())
+	}
+
+	if err := root.Remove(vol); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(vol.Path()); !os.IsNotExist(err) {
+		t.Fatalf("expected volume directory to be gone after Remove")
+	}
+}
+
+func TestCreateGeneratesName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volume-local-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := root.Create("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vol.Name() == "" {
+		t.Fatalf("expected a generated name for an anonymous volume")
+	}
+	if filepath.Dir(vol.Path()) != dir {
+		t.Fatalf("expected volume to be rooted at %s, got %s", dir, vol.Path())
+	}
+}
@@ -0,0 +1,35 @@
+// Package volume defines the interfaces that let volumes be managed by
+// pluggable backends instead of only anonymous directories under the graph
+// driver.
+package volume
+
+// Driver is implemented by a volume backend. It owns the full lifecycle of
+// the volumes it creates: allocation, lookup and removal.
+type Driver interface {
+	// Name returns the name the driver registers itself under.
+	Name() string
+	// Create allocates a new Volume with the given name, configured by
+	// driver-specific opts.
+	Create(name string, opts map[string]string) (Volume, error)
+	// Remove destroys a Volume previously returned by Create or Get.
+	Remove(Volume) error
+	// Get looks up an existing Volume by name.
+	Get(name string) (Volume, error)
+}
+
+// Volume is a unit of storage managed by a Driver and mountable into one or
+// more containers.
+type Volume interface {
+	// Name is the name the volume was created with.
+	Name() string
+	// DriverName returns the name of the driver that owns this volume.
+	DriverName() string
+	// Path returns the volume's location on the host, valid whether or
+	// not the volume is currently mounted.
+	Path() string
+	// Mount makes the volume available on the host and returns the path
+	// to bind into a container.
+	Mount() (string, error)
+	// Unmount releases the resources allocated by a previous Mount.
+	Unmount() error
+}
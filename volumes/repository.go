@@ -0,0 +1,135 @@
+// Package volumes tracks which containers reference which driver-backed
+// volumes, so a volume's on-disk data is only removed once nothing refers
+// to it any more.
+package volumes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// metadataFile is the name of the JSON file a Repository persists its
+// refcount table to, stored inside the directory it was created with.
+const metadataFile = "metadata.db"
+
+// entry is the persisted state for a single volume: which driver owns it
+// and which containers currently reference it.
+type entry struct {
+	Driver     string          `json:"driver"`
+	Containers map[string]bool `json:"containers"`
+}
+
+// Repository is a reference-counted registry of volume ID to container ID
+// associations, persisted as JSON so it survives a daemon restart.
+type Repository struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*entry // volume ID -> entry
+}
+
+// NewRepository opens (or creates) a Repository rooted at dir. existed
+// reports whether a metadata file was already present, so callers can tell
+// a fresh daemon root apart from an upgrade that needs reconciling.
+func NewRepository(dir string) (repo *Repository, existed bool, err error) {
+	if err := os.MkdirAll(dir, 0700); err != nil && !os.IsExist(err) {
+		return nil, false, err
+	}
+
+	repo = &Repository{
+		path:    filepath.Join(dir, metadataFile),
+		entries: make(map[string]*entry),
+	}
+
+	data, err := ioutil.ReadFile(repo.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return repo, false, nil
+		}
+		return nil, false, err
+	}
+
+	if err := json.Unmarshal(data, &repo.entries); err != nil {
+		return nil, false, err
+	}
+
+	return repo, true, nil
+}
+
+// Add records that containerID references the volume volumeID, owned by
+// driverName.
+func (r *Repository) Add(volumeID, driverName, containerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.entries[volumeID]
+	if e == nil {
+		e = &entry{Driver: driverName, Containers: make(map[string]bool)}
+		r.entries[volumeID] = e
+	}
+	e.Driver = driverName
+	e.Containers[containerID] = true
+
+	return r.save()
+}
+
+// Remove drops containerID's reference to volumeID and returns the
+// number of containers still referencing it.
+func (r *Repository) Remove(volumeID, containerID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.entries[volumeID]
+	if e != nil {
+		delete(e.Containers, containerID)
+		if len(e.Containers) == 0 {
+			delete(r.entries, volumeID)
+		}
+	}
+
+	if err := r.save(); err != nil {
+		return 0, err
+	}
+	return len(r.entries[volumeID].containers()), nil
+}
+
+// RefCount returns the number of containers currently referencing
+// volumeID.
+func (r *Repository) RefCount(volumeID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries[volumeID].containers())
+}
+
+// Driver returns the name of the driver that owns volumeID, if the
+// repository has a record of it.
+func (r *Repository) Driver(volumeID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, exists := r.entries[volumeID]
+	if !exists {
+		return "", false
+	}
+	return e.Driver, true
+}
+
+// containers returns e's container set, or nil for a nil entry, so
+// len(e.containers()) is always safe to call.
+func (e *entry) containers() map[string]bool {
+	if e == nil {
+		return nil
+	}
+	return e.Containers
+}
+
+// save persists the refcount table to disk. Callers must hold r.mu.
+func (r *Repository) save() error {
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0600)
+}
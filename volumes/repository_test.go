@@ -0,0 +1,108 @@
+package volumes
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAddTracksDriverAndRefcount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-repo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, existed, err := NewRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existed {
+		t.Fatalf("expected a fresh repository to report existed=false")
+	}
+
+	if err := repo.Add("vol1", "local", "container1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add("vol1", "local", "container2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs := repo.RefCount("vol1"); refs != 2 {
+		t.Fatalf("expected refcount 2, got %d", refs)
+	}
+
+	driverName, exists := repo.Driver("vol1")
+	if !exists {
+		t.Fatalf("expected vol1 to have a recorded driver")
+	}
+	if driverName != "local" {
+		t.Fatalf("expected driver %q, got %q", "local", driverName)
+	}
+
+	refs, err := repo.Remove("vol1", "container1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs != 1 {
+		t.Fatalf("expected refcount 1 after removing one reference, got %d", refs)
+	}
+
+	refs, err = repo.Remove("vol1", "container2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs != 0 {
+		t.Fatalf("expected refcount 0 after removing last reference, got %d", refs)
+	}
+
+	if _, exists := repo.Driver("vol1"); exists {
+		t.Fatalf("expected vol1's entry to be gone once its refcount reaches zero")
+	}
+}
+
+func TestDriverUnknownVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-repo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, _, err := NewRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, exists := repo.Driver("does-not-exist"); exists {
+		t.Fatalf("expected no driver recorded for an unknown volume")
+	}
+}
+
+func TestReopenRepositoryPersistsDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-volumes-repo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, _, err := NewRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add("vol1", "custom-driver", "container1"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, existed, err := NewRepository(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existed {
+		t.Fatalf("expected reopening an existing metadata file to report existed=true")
+	}
+
+	driverName, exists := reopened.Driver("vol1")
+	if !exists || driverName != "custom-driver" {
+		t.Fatalf("expected persisted driver %q, got %q (exists=%v)", "custom-driver", driverName, exists)
+	}
+}